@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"github.com/langgenius/dify-plugin-daemon/internal/db"
+	"github.com/langgenius/dify-plugin-daemon/internal/types/models"
+)
+
+const defaultListLimit = 100
+
+// List returns up to limit keys under prefix for (tenantId, pluginId),
+// ordered lexicographically. Pass the returned nextCursor back in to fetch
+// the following page; nextCursor is empty once there's nothing left.
+//
+// Backends that can scan by prefix natively (S3, GCS) are asked directly, so
+// the result reflects the real backend rather than the DB index, which can
+// drift from it. Backends without efficient prefix scans (local disk) fall
+// back to the TenantStorageEntry index, giving O(log n) pagination instead
+// of walking the filesystem on every call.
+func (c *Persistence) List(tenantId string, pluginId string, prefix string, cursor string, limit int) ([]KeyInfo, string, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	if c.storage.SupportsEfficientList() {
+		return c.storage.List(tenantId, pluginId, prefix, cursor, limit)
+	}
+
+	conditions := []db.Condition{
+		db.Equal("tenant_id", tenantId),
+		db.Equal("plugin_id", pluginId),
+	}
+	if prefix != "" {
+		conditions = append(conditions, db.Prefix("key", prefix))
+	}
+	if cursor != "" {
+		conditions = append(conditions, db.GreaterThan("key", cursor))
+	}
+	conditions = append(conditions, db.OrderAsc("key"), db.Limit(limit+1))
+
+	entries, err := db.GetAll[models.TenantStorageEntry](conditions...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries, nextCursor := paginate(entries, limit)
+
+	keys := make([]KeyInfo, len(entries))
+	for i, entry := range entries {
+		keys[i] = KeyInfo{
+			Key:       entry.Key,
+			Size:      entry.Size,
+			UpdatedAt: entry.UpdatedAt,
+		}
+	}
+
+	return keys, nextCursor, nil
+}
+
+// paginate slices entries (fetched with a limit of len(entries)+1, i.e. one
+// more than the caller asked for) down to at most limit items, and derives
+// the cursor to resume from: the last key on the page if there was a
+// (limit+1)th entry proving more data exists, or "" if entries was the whole
+// remaining tail.
+func paginate(entries []models.TenantStorageEntry, limit int) ([]models.TenantStorageEntry, string) {
+	if len(entries) <= limit {
+		return entries, ""
+	}
+	return entries[:limit], entries[limit-1].Key
+}
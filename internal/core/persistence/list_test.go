@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"testing"
+
+	"github.com/langgenius/dify-plugin-daemon/internal/types/models"
+)
+
+func entriesWithKeys(keys ...string) []models.TenantStorageEntry {
+	entries := make([]models.TenantStorageEntry, len(keys))
+	for i, k := range keys {
+		entries[i] = models.TenantStorageEntry{Key: k}
+	}
+	return entries
+}
+
+func TestPaginateReturnsEverythingWhenUnderLimit(t *testing.T) {
+	entries := entriesWithKeys("a", "b")
+
+	page, cursor := paginate(entries, 5)
+
+	if len(page) != 2 {
+		t.Fatalf("len(page) = %d, want 2", len(page))
+	}
+	if cursor != "" {
+		t.Fatalf("cursor = %q, want \"\" (nothing left to fetch)", cursor)
+	}
+}
+
+func TestPaginateReturnsEverythingWhenExactlyAtLimit(t *testing.T) {
+	// fetched with limit+1, so exactly `limit` entries means there was no
+	// (limit+1)th row and nothing more to page through.
+	entries := entriesWithKeys("a", "b")
+
+	page, cursor := paginate(entries, 2)
+
+	if len(page) != 2 {
+		t.Fatalf("len(page) = %d, want 2", len(page))
+	}
+	if cursor != "" {
+		t.Fatalf("cursor = %q, want \"\"", cursor)
+	}
+}
+
+func TestPaginateTruncatesAndReturnsCursorWhenMoreRemain(t *testing.T) {
+	// fetched with limit+1 = 3; a 3rd entry proves more data exists beyond
+	// the page.
+	entries := entriesWithKeys("a", "b", "c")
+
+	page, cursor := paginate(entries, 2)
+
+	if len(page) != 2 || page[0].Key != "a" || page[1].Key != "b" {
+		t.Fatalf("page = %v, want [a b]", page)
+	}
+	if cursor != "b" {
+		t.Fatalf("cursor = %q, want %q (last key on the returned page)", cursor, "b")
+	}
+}
+
+func TestPaginateEmptyInput(t *testing.T) {
+	page, cursor := paginate(nil, 10)
+
+	if len(page) != 0 {
+		t.Fatalf("len(page) = %d, want 0", len(page))
+	}
+	if cursor != "" {
+		t.Fatalf("cursor = %q, want \"\"", cursor)
+	}
+}
@@ -1,50 +1,140 @@
 package persistence
 
 import (
-	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/langgenius/dify-plugin-daemon/internal/db"
 	"github.com/langgenius/dify-plugin-daemon/internal/types/models"
-	"github.com/langgenius/dify-plugin-daemon/internal/utils/cache"
+	"github.com/langgenius/dify-plugin-daemon/internal/utils/cache/diskcache"
+	"github.com/langgenius/dify-plugin-daemon/internal/utils/cache/lfu"
 )
 
 type Persistence struct {
 	maxStorageSize int64
 
 	storage PersistenceStorage
+	cache   lfu.Cache
+
+	// disk is an optional second-tier cache sitting between cache and
+	// storage, enabled via EnableDiskCache. It is nil unless configured.
+	disk *diskcache.Cache
 }
 
 const (
 	CACHE_KEY_PREFIX = "persistence:cache"
+
+	// DefaultCacheSize is used when PERSISTENCE_CACHE_SIZE is unset.
+	DefaultCacheSize int64 = 64 * 1024 * 1024
 )
 
+// NewPersistence creates a Persistence backed by storage, enforcing
+// maxStorageSize as the default per-tenant quota and bounding the in-memory
+// read cache to cacheSize bytes (see PERSISTENCE_CACHE_SIZE).
+func NewPersistence(storage PersistenceStorage, maxStorageSize int64, cacheSize int64) *Persistence {
+	if cacheSize <= 0 {
+		cacheSize = DefaultCacheSize
+	}
+
+	return &Persistence{
+		maxStorageSize: maxStorageSize,
+		storage:        storage,
+		cache:          lfu.New(cacheSize),
+	}
+}
+
+// EnableDiskCache turns on the optional bbolt-backed second-tier cache,
+// bounding it to maxDiskBytes. It must be called before Load is used
+// concurrently from other goroutines.
+func (c *Persistence) EnableDiskCache(path string, maxDiskBytes int64) error {
+	disk, err := diskcache.Open(path, maxDiskBytes)
+	if err != nil {
+		return err
+	}
+
+	c.disk = disk
+	return nil
+}
+
 func (c *Persistence) getCacheKey(tenantId string, pluginId string, key string) string {
 	return fmt.Sprintf("%s:%s:%s:%s", CACHE_KEY_PREFIX, tenantId, pluginId, key)
 }
 
+// invalidateCache drops (tenantId, pluginId, key) from every cache tier. A
+// failed disk delete is returned rather than swallowed: leaving a stale
+// bbolt entry behind would make a deleted key readable again via the disk
+// cache tier in Load.
+func (c *Persistence) invalidateCache(tenantId string, pluginId string, key string) error {
+	c.cache.Del(c.getCacheKey(tenantId, pluginId, key))
+	if c.disk != nil {
+		return c.disk.Del(tenantId, pluginId, key)
+	}
+	return nil
+}
+
 func (c *Persistence) Save(tenantId string, pluginId string, maxSize int64, key string, data []byte) error {
+	return c.save(tenantId, pluginId, maxSize, key, data, nil)
+}
+
+// SaveWithTTL behaves like Save, but the key is automatically removed from
+// storage (and its accounted size freed) once ttl elapses. The sweep is
+// best-effort and runs on whatever cadence Run was started with, so actual
+// deletion may lag ttl by up to one sweep interval.
+func (c *Persistence) SaveWithTTL(tenantId string, pluginId string, maxSize int64, key string, data []byte, ttl time.Duration) error {
+	expireAt := time.Now().Add(ttl)
+	return c.save(tenantId, pluginId, maxSize, key, data, &expireAt)
+}
+
+func (c *Persistence) save(tenantId string, pluginId string, maxSize int64, key string, data []byte, expireAt *time.Time) error {
 	if len(key) > 256 {
 		return fmt.Errorf("key length must be less than 256 characters")
 	}
 
-	if maxSize == -1 {
-		maxSize = c.maxStorageSize
+	overQuota, err := c.tenantOverQuota(tenantId, pluginId)
+	if err != nil {
+		return err
+	}
+	if overQuota {
+		return fmt.Errorf("tenant %s is over its storage quota; remove data before writing more", tenantId)
 	}
 
+	effectiveCeiling, err := c.effectiveQuota(tenantId)
+	if err != nil {
+		return err
+	}
+
+	maxSize = resolveMaxSize(maxSize, effectiveCeiling)
+
 	if err := c.storage.Save(tenantId, pluginId, key, data); err != nil {
 		return err
 	}
 
-	allocatedSize := int64(len(data))
+	newSize := int64(len(data))
+
+	// resolve the previous size of this exact key, if any, so that
+	// overwriting a key adjusts the tenant's total by the delta rather than
+	// double-counting the old bytes.
+	var previousSize int64
+	existingEntry, entryErr := db.GetOne[models.TenantStorageEntry](
+		db.Equal("tenant_id", tenantId),
+		db.Equal("plugin_id", pluginId),
+		db.Equal("key", key),
+	)
+	entryIsNew := entryErr == db.ErrDatabaseNotFound
+	if entryErr == nil {
+		previousSize = existingEntry.Size
+	} else if !entryIsNew {
+		return entryErr
+	}
+
+	delta := computeSizeDelta(newSize, previousSize)
 
 	storage, err := db.GetOne[models.TenantStorage](
 		db.Equal("tenant_id", tenantId),
 		db.Equal("plugin_id", pluginId),
 	)
 	if err != nil {
-		if allocatedSize > c.maxStorageSize || allocatedSize > maxSize {
+		if !fitsWithinMaxSize(0, delta, maxSize) {
 			return fmt.Errorf("allocated size is greater than max storage size")
 		}
 
@@ -52,7 +142,7 @@ func (c *Persistence) Save(tenantId string, pluginId string, maxSize int64, key
 			storage = models.TenantStorage{
 				TenantID: tenantId,
 				PluginID: pluginId,
-				Size:     allocatedSize,
+				Size:     delta,
 			}
 			if err := db.Create(&storage); err != nil {
 				return err
@@ -61,7 +151,7 @@ func (c *Persistence) Save(tenantId string, pluginId string, maxSize int64, key
 			return err
 		}
 	} else {
-		if allocatedSize+storage.Size > maxSize || allocatedSize+storage.Size > c.maxStorageSize {
+		if !fitsWithinMaxSize(storage.Size, delta, maxSize) {
 			return fmt.Errorf("allocated size is greater than max storage size")
 		}
 
@@ -69,26 +159,82 @@ func (c *Persistence) Save(tenantId string, pluginId string, maxSize int64, key
 			db.Model(&models.TenantStorage{}),
 			db.Equal("tenant_id", tenantId),
 			db.Equal("plugin_id", pluginId),
-			db.Inc(map[string]int64{"size": allocatedSize}),
+			db.Inc(map[string]int64{"size": delta}),
 		)
 		if err != nil {
 			return err
 		}
 	}
 
+	if err := c.saveEntry(tenantId, pluginId, key, newSize, expireAt, entryIsNew); err != nil {
+		return err
+	}
+
 	// delete from cache
-	return cache.Del(c.getCacheKey(tenantId, pluginId, key))
+	return c.invalidateCache(tenantId, pluginId, key)
+}
+
+// computeSizeDelta returns how much a tenant's accounted size should change
+// by when a key's size goes from previousSize to newSize. previousSize is 0
+// for a brand-new key, so the delta is its full size; for an overwrite
+// (including one that lands before the old value's TTL expires) it's the
+// difference, so a same-size or shrinking overwrite never inflates the
+// tenant's usage.
+func computeSizeDelta(newSize int64, previousSize int64) int64 {
+	return newSize - previousSize
+}
+
+// resolveMaxSize returns the per-write cap save() enforces: requested
+// clamped to ceiling, or ceiling itself when requested asks for "no limit"
+// (-1) or for more than the tenant's effective quota allows.
+func resolveMaxSize(requested int64, ceiling int64) int64 {
+	if requested == -1 || requested > ceiling {
+		return ceiling
+	}
+	return requested
+}
+
+// fitsWithinMaxSize reports whether a write changing a tenant's accounted
+// size by delta, on top of its currentSize, stays within maxSize.
+func fitsWithinMaxSize(currentSize int64, delta int64, maxSize int64) bool {
+	return currentSize+delta <= maxSize
+}
+
+// saveEntry upserts the TenantStorageEntry row tracking key's size and
+// expiry.
+func (c *Persistence) saveEntry(tenantId string, pluginId string, key string, size int64, expireAt *time.Time, isNew bool) error {
+	if isNew {
+		return db.Create(&models.TenantStorageEntry{
+			TenantID:  tenantId,
+			PluginID:  pluginId,
+			Key:       key,
+			Size:      size,
+			ExpiresAt: expireAt,
+		})
+	}
+
+	return db.Run(
+		db.Model(&models.TenantStorageEntry{}),
+		db.Equal("tenant_id", tenantId),
+		db.Equal("plugin_id", pluginId),
+		db.Equal("key", key),
+		db.Set(map[string]any{"size": size, "expires_at": expireAt}),
+	)
 }
 
 // TODO: raises specific error to avoid confusion
 func (c *Persistence) Load(tenantId string, pluginId string, key string) ([]byte, error) {
-	// check if the key exists in cache
-	h, err := cache.GetString(c.getCacheKey(tenantId, pluginId, key))
-	if err != nil && err != cache.ErrNotFound {
-		return nil, err
+	// check if the key exists in the in-memory cache
+	if data, ok := c.cache.Get(c.getCacheKey(tenantId, pluginId, key)); ok {
+		return data, nil
 	}
-	if err == nil {
-		return hex.DecodeString(h)
+
+	// fall back to the on-disk cache, if enabled
+	if c.disk != nil {
+		if data, ok := c.disk.Get(tenantId, pluginId, key); ok {
+			c.cache.Set(c.getCacheKey(tenantId, pluginId, key), data, 5*time.Minute)
+			return data, nil
+		}
 	}
 
 	// load from storage
@@ -97,40 +243,93 @@ func (c *Persistence) Load(tenantId string, pluginId string, key string) ([]byte
 		return nil, err
 	}
 
-	// add to cache
-	cache.Store(c.getCacheKey(tenantId, pluginId, key), hex.EncodeToString(data), time.Minute*5)
+	// add to both cache tiers; a disk cache write failure is not fatal to
+	// the read that triggered it
+	c.cache.Set(c.getCacheKey(tenantId, pluginId, key), data, 5*time.Minute)
+	if c.disk != nil {
+		c.disk.Set(tenantId, pluginId, key, data)
+	}
 
 	return data, nil
 }
 
 func (c *Persistence) Delete(tenantId string, pluginId string, key string) error {
 	// delete from cache and storage
-	err := cache.Del(c.getCacheKey(tenantId, pluginId, key))
-	if err != nil {
+	if err := c.invalidateCache(tenantId, pluginId, key); err != nil {
 		return err
 	}
 
-	// state size
-	size, err := c.storage.StateSize(tenantId, pluginId, key)
-	if err != nil {
-		return nil
+	// prefer the TenantStorageEntry row's recorded size over asking storage
+	// directly, since it's also what has to be removed to keep List (and the
+	// expiration sweeper) from seeing a key whose blob is already gone.
+	entry, entryErr := db.GetOne[models.TenantStorageEntry](
+		db.Equal("tenant_id", tenantId),
+		db.Equal("plugin_id", pluginId),
+		db.Equal("key", key),
+	)
+	if entryErr != nil && entryErr != db.ErrDatabaseNotFound {
+		return entryErr
 	}
+	hasEntry := entryErr == nil
 
-	err = c.storage.Delete(tenantId, pluginId, key)
-	if err != nil {
+	size := int64(0)
+	if hasEntry {
+		size = entry.Size
+	} else {
+		var err error
+		size, err = c.storage.StateSize(tenantId, pluginId, key)
+		if err != nil {
+			return nil
+		}
+	}
+
+	if err := c.storage.Delete(tenantId, pluginId, key); err != nil {
 		return nil
 	}
 
-	// update storage size
-	err = db.Run(
-		db.Model(&models.TenantStorage{}),
-		db.Equal("tenant_id", tenantId),
-		db.Equal("plugin_id", pluginId),
-		db.Dec(map[string]int64{"size": size}),
-	)
-	if err != nil {
+	// remove the entry row and update the accounted size together so a
+	// crash or concurrent sweep can't leave one without the other.
+	if err := db.Transaction(func() error {
+		if hasEntry {
+			deletedRows, err := db.Delete[models.TenantStorageEntry](
+				db.Equal("tenant_id", tenantId),
+				db.Equal("plugin_id", pluginId),
+				db.Equal("key", key),
+			)
+			if err != nil {
+				return err
+			}
+			// the expiration sweeper removed the row (and decremented the
+			// size) first; decrementing again would double-subtract.
+			if deletedRows == 0 {
+				return nil
+			}
+		}
+
+		return db.Run(
+			db.Model(&models.TenantStorage{}),
+			db.Equal("tenant_id", tenantId),
+			db.Equal("plugin_id", pluginId),
+			db.Dec(map[string]int64{"size": size}),
+		)
+	}); err != nil {
 		return err
 	}
 
+	// deleting data may have brought the tenant back within its effective
+	// quota; clear a stale over-quota flag immediately rather than waiting
+	// for the next pack sweep.
+	if within, err := c.withinQuota(tenantId, pluginId); err == nil && within {
+		_ = c.clearOverQuota(tenantId, pluginId)
+	}
+
 	return nil
 }
+
+// SignedURL returns a time-limited, pre-signed GET URL for (tenantId,
+// pluginId, key) from the backing PersistenceStorage, for backends that
+// support it. It returns ErrSignedURLUnsupported on backends (like local
+// disk) that don't.
+func (c *Persistence) SignedURL(tenantId string, pluginId string, key string, expiresIn time.Duration) (string, error) {
+	return c.storage.SignedURL(tenantId, pluginId, key, expiresIn)
+}
@@ -0,0 +1,108 @@
+package persistence
+
+import "testing"
+
+// TestComputeSizeDeltaOverwriteBeforeExpiry covers the edge case save() relies
+// on to avoid double-counting a key's bytes when it's overwritten before its
+// previous TTL expires: the tenant's accounted size must move by the
+// difference between the two versions, not by the new version's full size.
+func TestComputeSizeDeltaOverwriteBeforeExpiry(t *testing.T) {
+	cases := []struct {
+		name         string
+		newSize      int64
+		previousSize int64
+		want         int64
+	}{
+		{"new key has no previous size", 100, 0, 100},
+		{"overwrite with a larger value", 150, 100, 50},
+		{"overwrite with a smaller value", 40, 100, -60},
+		{"overwrite with the same size", 100, 100, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := computeSizeDelta(tc.newSize, tc.previousSize); got != tc.want {
+				t.Fatalf("computeSizeDelta(%d, %d) = %d, want %d", tc.newSize, tc.previousSize, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestSaveOverwriteBeforeExpiryWithinQuota chains the same decision sequence
+// save() runs for an overwrite that lands before the previous value's TTL
+// expires: resolve the per-write cap, compute the size delta against the
+// prior entry, then check the resulting total against that cap. A tenant
+// sitting right at its ceiling must be able to shrink a key (or keep it the
+// same size) but not grow it further.
+func TestSaveOverwriteBeforeExpiryWithinQuota(t *testing.T) {
+	const ceiling = int64(1000)
+	const tenantSize = int64(1000) // already at the ceiling
+
+	maxSize := resolveMaxSize(-1, ceiling)
+	if maxSize != ceiling {
+		t.Fatalf("resolveMaxSize(-1, %d) = %d, want %d", ceiling, maxSize, ceiling)
+	}
+
+	// overwriting a 400-byte key with a same-size value changes nothing.
+	sameSizeDelta := computeSizeDelta(400, 400)
+	if !fitsWithinMaxSize(tenantSize, sameSizeDelta, maxSize) {
+		t.Fatalf("a same-size overwrite at the ceiling was rejected, want it to fit")
+	}
+
+	// shrinking the key frees headroom, so it must also fit.
+	shrinkDelta := computeSizeDelta(100, 400)
+	if !fitsWithinMaxSize(tenantSize, shrinkDelta, maxSize) {
+		t.Fatalf("a shrinking overwrite at the ceiling was rejected, want it to fit")
+	}
+
+	// growing the key past the ceiling must be rejected.
+	growDelta := computeSizeDelta(500, 400)
+	if fitsWithinMaxSize(tenantSize, growDelta, maxSize) {
+		t.Fatalf("a growing overwrite past the ceiling was accepted, want it rejected")
+	}
+}
+
+func TestResolveMaxSize(t *testing.T) {
+	cases := []struct {
+		name      string
+		requested int64
+		ceiling   int64
+		want      int64
+	}{
+		{"no limit requested uses the ceiling", -1, 1000, 1000},
+		{"requested above the ceiling is clamped", 5000, 1000, 1000},
+		{"requested within the ceiling is kept", 500, 1000, 500},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := resolveMaxSize(tc.requested, tc.ceiling); got != tc.want {
+				t.Fatalf("resolveMaxSize(%d, %d) = %d, want %d", tc.requested, tc.ceiling, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFitsWithinMaxSize(t *testing.T) {
+	cases := []struct {
+		name        string
+		currentSize int64
+		delta       int64
+		maxSize     int64
+		want        bool
+	}{
+		{"new key fits", 0, 100, 1000, true},
+		{"new key exceeds cap", 0, 2000, 1000, false},
+		{"overwrite delta exactly fills the cap", 900, 100, 1000, true},
+		{"overwrite delta exceeds the cap by one byte", 900, 101, 1000, false},
+		{"negative delta (shrink) from the cap fits", 1000, -500, 1000, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fitsWithinMaxSize(tc.currentSize, tc.delta, tc.maxSize); got != tc.want {
+				t.Fatalf("fitsWithinMaxSize(%d, %d, %d) = %v, want %v", tc.currentSize, tc.delta, tc.maxSize, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/langgenius/dify-plugin-daemon/internal/db"
+	"github.com/langgenius/dify-plugin-daemon/internal/types/models"
+)
+
+// AddStoragePack grants tenantId extraSizeBytes of additional storage quota
+// for the window [activeAt, expiresAt). Since this raises the tenant's
+// ceiling immediately if activeAt has already passed, it also clears any
+// stale over-quota flags the new headroom resolves.
+func (c *Persistence) AddStoragePack(tenantId string, extraSizeBytes int64, activeAt time.Time, expiresAt time.Time) error {
+	if err := db.Create(&models.TenantStoragePack{
+		TenantID:       tenantId,
+		ExtraSizeBytes: extraSizeBytes,
+		ActiveAt:       activeAt,
+		ExpiresAt:      expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	return c.refreshTenantOverQuota(tenantId)
+}
+
+// ListStoragePacks returns every storage pack ever granted to tenantId,
+// active or not.
+func (c *Persistence) ListStoragePacks(tenantId string) ([]models.TenantStoragePack, error) {
+	return db.GetAll[models.TenantStoragePack](
+		db.Equal("tenant_id", tenantId),
+	)
+}
+
+// RevokeStoragePack immediately stops a pack from counting towards a
+// tenant's effective quota by expiring it.
+func (c *Persistence) RevokeStoragePack(packId uint) error {
+	return db.Run(
+		db.Model(&models.TenantStoragePack{}),
+		db.Equal("id", packId),
+		db.Set(map[string]any{"expires_at": time.Now()}),
+	)
+}
+
+// effectiveQuota returns the base quota plus every currently-active pack
+// granted to tenantId. The active-window check and the summing are both
+// plain Go (packActive/sumExtraSizeBytes) rather than DB-side conditions,
+// so the ceiling math itself is unit-testable without a database.
+func (c *Persistence) effectiveQuota(tenantId string) (int64, error) {
+	packs, err := db.GetAll[models.TenantStoragePack](
+		db.Equal("tenant_id", tenantId),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.maxStorageSize + sumExtraSizeBytes(packs, time.Now()), nil
+}
+
+// packActive reports whether pack is within its [ActiveAt, ExpiresAt) window
+// at instant now.
+func packActive(pack models.TenantStoragePack, now time.Time) bool {
+	return !now.Before(pack.ActiveAt) && now.Before(pack.ExpiresAt)
+}
+
+// sumExtraSizeBytes totals ExtraSizeBytes across every pack in packs that is
+// active at now.
+func sumExtraSizeBytes(packs []models.TenantStoragePack, now time.Time) int64 {
+	var total int64
+	for _, pack := range packs {
+		if packActive(pack, now) {
+			total += pack.ExtraSizeBytes
+		}
+	}
+	return total
+}
+
+// tenantOverQuota reports whether (tenantId, pluginId) currently has any
+// entries flagged over-quota. Usage may have dropped back within the
+// effective ceiling since the flag was set (data deleted, a new pack
+// bought), so it's first given the chance to self-heal: if usage now fits,
+// the flag is cleared here rather than left to bar writes forever.
+func (c *Persistence) tenantOverQuota(tenantId string, pluginId string) (bool, error) {
+	within, err := c.withinQuota(tenantId, pluginId)
+	if err != nil {
+		return false, err
+	}
+	if within {
+		return false, c.clearOverQuota(tenantId, pluginId)
+	}
+
+	entries, err := db.GetAll[models.TenantStorageEntry](
+		db.Equal("tenant_id", tenantId),
+		db.Equal("plugin_id", pluginId),
+		db.Equal("over_quota", true),
+		db.Limit(1),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return len(entries) > 0, nil
+}
+
+// withinQuota reports whether (tenantId, pluginId)'s currently accounted
+// size fits inside its effective quota.
+func (c *Persistence) withinQuota(tenantId string, pluginId string) (bool, error) {
+	ceiling, err := c.effectiveQuota(tenantId)
+	if err != nil {
+		return false, err
+	}
+
+	storage, err := db.GetOne[models.TenantStorage](
+		db.Equal("tenant_id", tenantId),
+		db.Equal("plugin_id", pluginId),
+	)
+	if err != nil {
+		if err == db.ErrDatabaseNotFound {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return isWithinQuota(storage.Size, ceiling), nil
+}
+
+// isWithinQuota reports whether size fits inside ceiling.
+func isWithinQuota(size int64, ceiling int64) bool {
+	return size <= ceiling
+}
+
+// clearOverQuota unflags every over-quota entry for (tenantId, pluginId). It
+// is a no-op if none are flagged.
+func (c *Persistence) clearOverQuota(tenantId string, pluginId string) error {
+	return db.Run(
+		db.Model(&models.TenantStorageEntry{}),
+		db.Equal("tenant_id", tenantId),
+		db.Equal("plugin_id", pluginId),
+		db.Equal("over_quota", true),
+		db.Set(map[string]any{"over_quota": false}),
+	)
+}
+
+// refreshTenantOverQuota re-checks every (tenantId, pluginId) pair currently
+// carrying an over-quota flag and clears it if usage now fits the effective
+// quota, e.g. right after a new TenantStoragePack raises the ceiling.
+func (c *Persistence) refreshTenantOverQuota(tenantId string) error {
+	flaggedPlugins, err := c.flaggedPlugins(tenantId)
+	if err != nil {
+		return err
+	}
+
+	for _, pluginId := range flaggedPlugins {
+		within, err := c.withinQuota(tenantId, pluginId)
+		if err != nil {
+			return err
+		}
+		if within {
+			if err := c.clearOverQuota(tenantId, pluginId); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// flaggedPlugins returns the distinct pluginIds for which tenantId currently
+// has at least one over-quota entry.
+func (c *Persistence) flaggedPlugins(tenantId string) ([]string, error) {
+	entries, err := db.GetAll[models.TenantStorageEntry](
+		db.Equal("tenant_id", tenantId),
+		db.Equal("over_quota", true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(entries))
+	plugins := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !seen[entry.PluginID] {
+			seen[entry.PluginID] = true
+			plugins = append(plugins, entry.PluginID)
+		}
+	}
+
+	return plugins, nil
+}
+
+// sweepExpiredPacks recomputes every tenant's effective quota. Tenants now
+// over it have their newest entries flagged over-quota until the flagged
+// total covers the excess; tenants back within it (a pack expired downward
+// but they've since deleted data, or a new pack landed) have any stale flags
+// cleared. Flagged data is kept, not deleted, so the tenant can choose what
+// to remove.
+func (c *Persistence) sweepExpiredPacks() {
+	storages, err := db.GetAll[models.TenantStorage]()
+	if err != nil {
+		return
+	}
+
+	for _, storage := range storages {
+		ceiling, err := c.effectiveQuota(storage.TenantID)
+		if err != nil {
+			continue
+		}
+
+		excess := storage.Size - ceiling
+		if excess <= 0 {
+			_ = c.clearOverQuota(storage.TenantID, storage.PluginID)
+			continue
+		}
+
+		c.flagOverQuota(storage.TenantID, storage.PluginID, excess)
+	}
+}
+
+// flagOverQuota marks the newest entries for (tenantId, pluginId) as
+// over-quota until their combined size covers excess bytes. Entries already
+// flagged by an earlier sweep count towards that total.
+func (c *Persistence) flagOverQuota(tenantId string, pluginId string, excess int64) {
+	entries, err := db.GetAll[models.TenantStorageEntry](
+		db.Equal("tenant_id", tenantId),
+		db.Equal("plugin_id", pluginId),
+		db.OrderDesc("created_at"),
+	)
+	if err != nil {
+		return
+	}
+
+	var flagged int64
+	for _, entry := range entries {
+		if flagged >= excess {
+			break
+		}
+
+		if !entry.OverQuota {
+			if err := db.Run(
+				db.Model(&models.TenantStorageEntry{}),
+				db.Equal("id", entry.ID),
+				db.Set(map[string]any{"over_quota": true}),
+			); err != nil {
+				continue
+			}
+		}
+
+		flagged += entry.Size
+	}
+}
@@ -0,0 +1,92 @@
+package persistence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/langgenius/dify-plugin-daemon/internal/types/models"
+)
+
+func TestPackActiveWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name string
+		pack models.TenantStoragePack
+		want bool
+	}{
+		{
+			name: "within window",
+			pack: models.TenantStoragePack{ActiveAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+			want: true,
+		},
+		{
+			name: "not yet active",
+			pack: models.TenantStoragePack{ActiveAt: now.Add(time.Hour), ExpiresAt: now.Add(2 * time.Hour)},
+			want: false,
+		},
+		{
+			name: "already expired",
+			pack: models.TenantStoragePack{ActiveAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)},
+			want: false,
+		},
+		{
+			name: "active_at exactly now is active",
+			pack: models.TenantStoragePack{ActiveAt: now, ExpiresAt: now.Add(time.Hour)},
+			want: true,
+		},
+		{
+			name: "expires_at exactly now is no longer active",
+			pack: models.TenantStoragePack{ActiveAt: now.Add(-time.Hour), ExpiresAt: now},
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := packActive(tc.pack, now); got != tc.want {
+				t.Fatalf("packActive() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSumExtraSizeBytesOnlyCountsActivePacks(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	packs := []models.TenantStoragePack{
+		{ExtraSizeBytes: 100, ActiveAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},  // active
+		{ExtraSizeBytes: 200, ActiveAt: now.Add(time.Hour), ExpiresAt: now.Add(2 * time.Hour)}, // not yet active
+		{ExtraSizeBytes: 300, ActiveAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}, // expired
+		{ExtraSizeBytes: 50, ActiveAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Minute)}, // active
+	}
+
+	if got, want := sumExtraSizeBytes(packs, now), int64(150); got != want {
+		t.Fatalf("sumExtraSizeBytes() = %d, want %d", got, want)
+	}
+}
+
+func TestSumExtraSizeBytesEmpty(t *testing.T) {
+	if got := sumExtraSizeBytes(nil, time.Now()); got != 0 {
+		t.Fatalf("sumExtraSizeBytes(nil) = %d, want 0", got)
+	}
+}
+
+func TestIsWithinQuota(t *testing.T) {
+	cases := []struct {
+		size    int64
+		ceiling int64
+		want    bool
+	}{
+		{size: 50, ceiling: 100, want: true},
+		{size: 100, ceiling: 100, want: true},
+		{size: 101, ceiling: 100, want: false},
+		{size: 0, ceiling: 0, want: true},
+	}
+
+	for _, tc := range cases {
+		if got := isWithinQuota(tc.size, tc.ceiling); got != tc.want {
+			t.Fatalf("isWithinQuota(%d, %d) = %v, want %v", tc.size, tc.ceiling, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package persistence
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KeyInfo describes a single key returned by PersistenceStorage.List or
+// Persistence.List.
+type KeyInfo struct {
+	Key       string
+	Size      int64
+	UpdatedAt time.Time
+}
+
+// PersistenceStorage abstracts the blob store backing Persistence. Every
+// object is namespaced by tenant and plugin so implementations never need
+// to worry about cross-tenant collisions.
+type PersistenceStorage interface {
+	Save(tenantId string, pluginId string, key string, data []byte) error
+	Load(tenantId string, pluginId string, key string) ([]byte, error)
+	Delete(tenantId string, pluginId string, key string) error
+	StateSize(tenantId string, pluginId string, key string) (int64, error)
+
+	// List returns up to limit keys under prefix, ordered so that passing
+	// the returned nextCursor back in resumes exactly where this call left
+	// off. nextCursor is empty once there is nothing left to list.
+	List(tenantId string, pluginId string, prefix string, cursor string, limit int) (keys []KeyInfo, nextCursor string, err error)
+
+	// SupportsEfficientList reports whether List can serve a prefix scan
+	// natively (e.g. S3/GCS's list-with-prefix APIs) without walking every
+	// object. Persistence.List uses this to decide whether to call List
+	// directly or fall back to its own DB-backed index.
+	SupportsEfficientList() bool
+
+	// SignedURL returns a time-limited, pre-signed GET URL for (tenantId,
+	// pluginId, key), for backends that support it. Backends without a
+	// notion of signed reads (local disk) return ErrSignedURLUnsupported.
+	SignedURL(tenantId string, pluginId string, key string, expiresIn time.Duration) (string, error)
+}
+
+// ErrSignedURLUnsupported is returned by SignedURL on backends with no
+// notion of a pre-signed read URL, such as LocalStorage.
+var ErrSignedURLUnsupported = fmt.Errorf("persistence storage backend does not support signed urls")
+
+// StorageConfig holds the settings for every backend NewPersistenceStorage
+// knows how to build. Only the section matching the selected scheme is read.
+type StorageConfig struct {
+	// Local is the root directory used by the local:// scheme. When set it
+	// overrides the path in the uri, as S3.Bucket and GCS.Bucket do.
+	Local string
+
+	S3  S3StorageConfig
+	GCS GCSStorageConfig
+}
+
+// NewPersistenceStorage builds a PersistenceStorage implementation selected
+// by the scheme of uri, e.g. "local:///var/data/persistence",
+// "s3://my-bucket/plugins" or "gs://my-bucket/plugins".
+func NewPersistenceStorage(uri string, config StorageConfig) (PersistenceStorage, error) {
+	scheme, rest, found := strings.Cut(uri, "://")
+	if !found {
+		return nil, fmt.Errorf("invalid persistence storage uri %q: missing scheme", uri)
+	}
+
+	switch scheme {
+	case "local":
+		if config.Local != "" {
+			rest = config.Local
+		}
+		return NewLocalStorage(rest)
+	case "s3":
+		return NewS3Storage(rest, config.S3)
+	case "gs":
+		return NewGCSStorage(rest, config.GCS)
+	default:
+		return nil, fmt.Errorf("unsupported persistence storage scheme %q", scheme)
+	}
+}
+
+// objectKey builds the canonical object path shared by every remote backend:
+// <prefix>/<tenantId>/<pluginId>/<key>.
+func objectKey(prefix string, tenantId string, pluginId string, key string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return fmt.Sprintf("%s/%s/%s", tenantId, pluginId, key)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", prefix, tenantId, pluginId, key)
+}
+
+// withRetry retries fn with exponential backoff, used by the remote storage
+// backends to ride out transient network/API errors.
+func withRetry(attempts int, baseDelay time.Duration, fn func() error) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if i < attempts-1 {
+			time.Sleep(baseDelay * (1 << i))
+		}
+	}
+	return err
+}
+
+// validatePathComponent rejects a tenantId/pluginId value that could be used
+// to escape the per-tenant namespace a backend builds paths from: empty,
+// ".", "..", or anything containing a path separator.
+func validatePathComponent(name string, value string) error {
+	if value == "" || value == "." || value == ".." || strings.ContainsAny(value, "/\\") {
+		return fmt.Errorf("persistence storage %s %q is not a valid path component", name, value)
+	}
+	return nil
+}
+
+// validateKey rejects an empty key, or one made only of separators, or one
+// with a "." or ".." path segment, which could otherwise be used to traverse
+// outside the tenant/plugin directory a backend scopes it under. Empty
+// segments inside a key (as in "a//b") are skipped, not rejected.
+func validateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("persistence storage key must not be empty")
+	}
+
+	segments := strings.FieldsFunc(key, func(r rune) bool { return r == '/' || r == '\\' })
+	if len(segments) == 0 {
+		return fmt.Errorf("persistence storage key %q must not be empty", key)
+	}
+	for _, segment := range segments {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("persistence storage key %q contains an invalid path segment", key)
+		}
+	}
+	return nil
+}
+
+// validateObjectPath runs validatePathComponent/validateKey over a full
+// (tenantId, pluginId, key) triple. Every PersistenceStorage method that
+// turns these into a path (Save, Load, Delete, StateSize) calls this first.
+func validateObjectPath(tenantId string, pluginId string, key string) error {
+	if err := validatePathComponent("tenantId", tenantId); err != nil {
+		return err
+	}
+	if err := validatePathComponent("pluginId", pluginId); err != nil {
+		return err
+	}
+	return validateKey(key)
+}
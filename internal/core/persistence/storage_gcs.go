@@ -0,0 +1,290 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+const (
+	gcsRetryAttempts = 3
+	gcsRetryBaseWait = 200 * time.Millisecond
+)
+
+// GCSStorageConfig configures the gs:// PersistenceStorage backend.
+type GCSStorageConfig struct {
+	Endpoint        string
+	CredentialsJSON string
+	Bucket          string
+	PathPrefix      string
+
+	URLPrefix string
+	URLSuffix string
+}
+
+// gcsObject is the subset of *storage.ObjectHandle Save/Load/Delete/
+// StateSize need. Narrowing to an interface (with gcsBucket/gcsClient below)
+// lets tests back GCSStorage with a fake instead of a real bucket.
+type gcsObject interface {
+	NewWriter(ctx context.Context) io.WriteCloser
+	NewReader(ctx context.Context) (io.ReadCloser, error)
+	Delete(ctx context.Context) error
+	Attrs(ctx context.Context) (*storage.ObjectAttrs, error)
+}
+
+// gcsBucket is the subset of *storage.BucketHandle List/SignedURL need, plus
+// the Object accessor the other methods need.
+type gcsBucket interface {
+	Object(name string) gcsObject
+	Objects(ctx context.Context, q *storage.Query) *storage.ObjectIterator
+	SignedURL(name string, opts *storage.SignedURLOptions) (string, error)
+}
+
+// gcsClient is the subset of *storage.Client GCSStorage needs.
+type gcsClient interface {
+	Bucket(name string) gcsBucket
+}
+
+type realGCSClient struct{ client *storage.Client }
+
+func (r realGCSClient) Bucket(name string) gcsBucket {
+	return realGCSBucket{r.client.Bucket(name)}
+}
+
+type realGCSBucket struct{ bucket *storage.BucketHandle }
+
+func (r realGCSBucket) Object(name string) gcsObject {
+	return realGCSObject{r.bucket.Object(name)}
+}
+
+func (r realGCSBucket) Objects(ctx context.Context, q *storage.Query) *storage.ObjectIterator {
+	return r.bucket.Objects(ctx, q)
+}
+
+func (r realGCSBucket) SignedURL(name string, opts *storage.SignedURLOptions) (string, error) {
+	return r.bucket.SignedURL(name, opts)
+}
+
+type realGCSObject struct{ object *storage.ObjectHandle }
+
+func (r realGCSObject) NewWriter(ctx context.Context) io.WriteCloser {
+	return r.object.NewWriter(ctx)
+}
+
+func (r realGCSObject) NewReader(ctx context.Context) (io.ReadCloser, error) {
+	return r.object.NewReader(ctx)
+}
+
+func (r realGCSObject) Delete(ctx context.Context) error {
+	return r.object.Delete(ctx)
+}
+
+func (r realGCSObject) Attrs(ctx context.Context) (*storage.ObjectAttrs, error) {
+	return r.object.Attrs(ctx)
+}
+
+// GCSStorage implements PersistenceStorage backed by a Google Cloud Storage
+// bucket.
+type GCSStorage struct {
+	client gcsClient
+	bucket string
+	prefix string
+	cfg    GCSStorageConfig
+}
+
+// NewGCSStorage builds a GCSStorage. bucket is the path component parsed out
+// of the gs:// uri (gs://<bucket>/<path-prefix>); cfg.Bucket overrides it
+// when set explicitly.
+func NewGCSStorage(bucket string, cfg GCSStorageConfig) (*GCSStorage, error) {
+	bucketName, pathPrefix, _ := strings.Cut(bucket, "/")
+	if cfg.Bucket != "" {
+		bucketName = cfg.Bucket
+	}
+	if cfg.PathPrefix != "" {
+		pathPrefix = cfg.PathPrefix
+	}
+	if bucketName == "" {
+		return nil, fmt.Errorf("gcs persistence storage requires a bucket")
+	}
+
+	opts := []option.ClientOption{}
+	if cfg.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.CredentialsJSON != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(cfg.CredentialsJSON)))
+	}
+
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GCSStorage{
+		client: realGCSClient{client: client},
+		bucket: bucketName,
+		prefix: pathPrefix,
+		cfg:    cfg,
+	}, nil
+}
+
+func (s *GCSStorage) key(tenantId string, pluginId string, key string) string {
+	return objectKey(s.prefix, tenantId, pluginId, key)
+}
+
+func (s *GCSStorage) Save(tenantId string, pluginId string, key string, data []byte) error {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	return withRetry(gcsRetryAttempts, gcsRetryBaseWait, func() error {
+		ctx := context.Background()
+		w := s.client.Bucket(s.bucket).Object(objKey).NewWriter(ctx)
+		if _, err := w.Write(data); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	})
+}
+
+func (s *GCSStorage) Load(tenantId string, pluginId string, key string) ([]byte, error) {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return nil, err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	var data []byte
+	err := withRetry(gcsRetryAttempts, gcsRetryBaseWait, func() error {
+		ctx := context.Background()
+		r, err := s.client.Bucket(s.bucket).Object(objKey).NewReader(ctx)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		data, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *GCSStorage) Delete(tenantId string, pluginId string, key string) error {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	return withRetry(gcsRetryAttempts, gcsRetryBaseWait, func() error {
+		err := s.client.Bucket(s.bucket).Object(objKey).Delete(context.Background())
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil
+		}
+		return err
+	})
+}
+
+// SupportsEfficientList reports true: the Objects iterator scans by prefix
+// natively.
+func (s *GCSStorage) SupportsEfficientList() bool {
+	return true
+}
+
+func (s *GCSStorage) List(tenantId string, pluginId string, prefix string, cursor string, limit int) ([]KeyInfo, string, error) {
+	if err := validatePathComponent("tenantId", tenantId); err != nil {
+		return nil, "", err
+	}
+	if err := validatePathComponent("pluginId", pluginId); err != nil {
+		return nil, "", err
+	}
+
+	listPrefix := s.key(tenantId, pluginId, prefix)
+	trimPrefix := s.key(tenantId, pluginId, "")
+
+	it := s.client.Bucket(s.bucket).Objects(context.Background(), &storage.Query{Prefix: listPrefix})
+	pager := iterator.NewPager(it, limit, cursor)
+
+	var objs []*storage.ObjectAttrs
+	nextCursor, err := pager.NextPage(&objs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keys := make([]KeyInfo, 0, len(objs))
+	for _, attrs := range objs {
+		keys = append(keys, KeyInfo{
+			Key:       strings.TrimPrefix(attrs.Name, trimPrefix),
+			Size:      attrs.Size,
+			UpdatedAt: attrs.Updated,
+		})
+	}
+
+	return keys, nextCursor, nil
+}
+
+func (s *GCSStorage) StateSize(tenantId string, pluginId string, key string) (int64, error) {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return 0, err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	var size int64
+	err := withRetry(gcsRetryAttempts, gcsRetryBaseWait, func() error {
+		attrs, err := s.client.Bucket(s.bucket).Object(objKey).Attrs(context.Background())
+		if err != nil {
+			return err
+		}
+		size = attrs.Size
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// SignedURL returns a time-limited, V4-signed GET URL for (tenantId,
+// pluginId, key), with cfg.URLPrefix/URLSuffix applied so operators that
+// proxy or CDN-front the bucket can rewrite it to a reachable address.
+func (s *GCSStorage) SignedURL(tenantId string, pluginId string, key string, expiresIn time.Duration) (string, error) {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return "", err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	opts := &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  "GET",
+		Expires: time.Now().Add(expiresIn),
+	}
+
+	var signedURL string
+	err := withRetry(gcsRetryAttempts, gcsRetryBaseWait, func() error {
+		var err error
+		signedURL, err = s.client.Bucket(s.bucket).SignedURL(objKey, opts)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.cfg.URLPrefix + signedURL + s.cfg.URLSuffix, nil
+}
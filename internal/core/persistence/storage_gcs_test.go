@@ -0,0 +1,144 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"cloud.google.com/go/storage"
+)
+
+// fakeGCSObject is an in-memory stand-in for gcsObject, backing a single
+// object's bytes so Save/Load can be exercised without a real bucket.
+type fakeGCSObject struct {
+	bucket *fakeGCSBucket
+	name   string
+}
+
+type fakeGCSWriter struct {
+	bytes.Buffer
+	object *fakeGCSObject
+}
+
+func (w *fakeGCSWriter) Close() error {
+	w.object.bucket.objects[w.object.name] = append([]byte(nil), w.Bytes()...)
+	return nil
+}
+
+func (o *fakeGCSObject) NewWriter(ctx context.Context) io.WriteCloser {
+	return &fakeGCSWriter{object: o}
+}
+
+func (o *fakeGCSObject) NewReader(ctx context.Context) (io.ReadCloser, error) {
+	data, ok := o.bucket.objects[o.name]
+	if !ok {
+		return nil, storage.ErrObjectNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (o *fakeGCSObject) Delete(ctx context.Context) error {
+	if _, ok := o.bucket.objects[o.name]; !ok {
+		return storage.ErrObjectNotExist
+	}
+	delete(o.bucket.objects, o.name)
+	return nil
+}
+
+func (o *fakeGCSObject) Attrs(ctx context.Context) (*storage.ObjectAttrs, error) {
+	data, ok := o.bucket.objects[o.name]
+	if !ok {
+		return nil, storage.ErrObjectNotExist
+	}
+	return &storage.ObjectAttrs{Name: o.name, Size: int64(len(data))}, nil
+}
+
+type fakeGCSBucket struct {
+	objects map[string][]byte
+}
+
+func (b *fakeGCSBucket) Object(name string) gcsObject {
+	return &fakeGCSObject{bucket: b, name: name}
+}
+
+func (b *fakeGCSBucket) Objects(ctx context.Context, q *storage.Query) *storage.ObjectIterator {
+	return nil
+}
+
+func (b *fakeGCSBucket) SignedURL(name string, opts *storage.SignedURLOptions) (string, error) {
+	return "https://example.invalid/" + name, nil
+}
+
+type fakeGCSClient struct {
+	bucket *fakeGCSBucket
+}
+
+func (c *fakeGCSClient) Bucket(name string) gcsBucket {
+	return c.bucket
+}
+
+func newTestGCSStorage() *GCSStorage {
+	return &GCSStorage{
+		client: &fakeGCSClient{bucket: &fakeGCSBucket{objects: make(map[string][]byte)}},
+		bucket: "test-bucket",
+	}
+}
+
+func TestGCSStorageSaveLoadLargeBlob(t *testing.T) {
+	s := newTestGCSStorage()
+
+	// 8MiB, large enough that a naive implementation buffering into a
+	// fixed-size intermediate would truncate or corrupt it.
+	large := bytes.Repeat([]byte("0123456789abcdef"), (8<<20)/16)
+
+	if err := s.Save("tenant", "plugin", "blob", large); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := s.Load("tenant", "plugin", "blob")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !bytes.Equal(got, large) {
+		t.Fatalf("Load returned %d bytes, want %d bytes matching the original", len(got), len(large))
+	}
+
+	size, err := s.StateSize("tenant", "plugin", "blob")
+	if err != nil {
+		t.Fatalf("StateSize: %v", err)
+	}
+	if size != int64(len(large)) {
+		t.Fatalf("StateSize = %d, want %d", size, len(large))
+	}
+}
+
+func TestGCSStorageDeleteRemovesObject(t *testing.T) {
+	s := newTestGCSStorage()
+
+	if err := s.Save("tenant", "plugin", "key", []byte("data")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := s.Delete("tenant", "plugin", "key"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Load("tenant", "plugin", "key"); err == nil {
+		t.Fatalf("Load after Delete: expected an error, got nil")
+	}
+}
+
+func TestGCSStorageSignedURLUsesPrefixAndSuffix(t *testing.T) {
+	s := newTestGCSStorage()
+	s.cfg = GCSStorageConfig{URLPrefix: "https://cdn.example.com/", URLSuffix: "?cache=1"}
+
+	url, err := s.SignedURL("tenant", "plugin", "key", 0)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	if !bytes.HasPrefix([]byte(url), []byte(s.cfg.URLPrefix)) {
+		t.Fatalf("SignedURL() = %q, want prefix %q", url, s.cfg.URLPrefix)
+	}
+	if !bytes.HasSuffix([]byte(url), []byte(s.cfg.URLSuffix)) {
+		t.Fatalf("SignedURL() = %q, want suffix %q", url, s.cfg.URLSuffix)
+	}
+}
@@ -0,0 +1,150 @@
+package persistence
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LocalStorage implements PersistenceStorage on top of the local filesystem,
+// laying objects out as <root>/<tenantId>/<pluginId>/<key>.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates a LocalStorage rooted at root, creating the
+// directory if it does not already exist.
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if root == "" {
+		return nil, fmt.Errorf("local persistence storage requires a root directory")
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{root: root}, nil
+}
+
+// path joins tenantId/pluginId/key onto s.root. Callers must validate the
+// components with validateObjectPath first: this performs no sanitization
+// itself, and a ".."-laden component would otherwise escape s.root.
+func (s *LocalStorage) path(tenantId string, pluginId string, key string) string {
+	return filepath.Join(s.root, tenantId, pluginId, key)
+}
+
+func (s *LocalStorage) Save(tenantId string, pluginId string, key string, data []byte) error {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return err
+	}
+
+	p := s.path(tenantId, pluginId, key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+func (s *LocalStorage) Load(tenantId string, pluginId string, key string) ([]byte, error) {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(s.path(tenantId, pluginId, key))
+}
+
+func (s *LocalStorage) Delete(tenantId string, pluginId string, key string) error {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return err
+	}
+
+	err := os.Remove(s.path(tenantId, pluginId, key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStorage) StateSize(tenantId string, pluginId string, key string) (int64, error) {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(s.path(tenantId, pluginId, key))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// SupportsEfficientList reports false: the filesystem has no native prefix
+// index, so List has to walk the whole tenant/plugin directory.
+func (s *LocalStorage) SupportsEfficientList() bool {
+	return false
+}
+
+// SignedURL always returns ErrSignedURLUnsupported: local disk has no
+// notion of a pre-signed read URL.
+func (s *LocalStorage) SignedURL(tenantId string, pluginId string, key string, expiresIn time.Duration) (string, error) {
+	return "", ErrSignedURLUnsupported
+}
+
+// List walks the tenant/plugin directory under prefix. The filesystem has
+// no native pagination, so it collects every matching key, sorts it for a
+// stable order, and slices out the requested page itself.
+func (s *LocalStorage) List(tenantId string, pluginId string, prefix string, cursor string, limit int) ([]KeyInfo, string, error) {
+	if err := validatePathComponent("tenantId", tenantId); err != nil {
+		return nil, "", err
+	}
+	if err := validatePathComponent("pluginId", pluginId); err != nil {
+		return nil, "", err
+	}
+
+	root := filepath.Join(s.root, tenantId, pluginId)
+
+	var keys []KeyInfo
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		key := filepath.ToSlash(strings.TrimPrefix(strings.TrimPrefix(p, root), "/"))
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		keys = append(keys, KeyInfo{Key: key, Size: info.Size(), UpdatedAt: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+
+	start := 0
+	if cursor != "" {
+		start = sort.Search(len(keys), func(i int) bool { return keys[i].Key > cursor })
+	}
+	if start >= len(keys) {
+		return nil, "", nil
+	}
+
+	end := start + limit
+	nextCursor := ""
+	if limit > 0 && end < len(keys) {
+		nextCursor = keys[end-1].Key
+	} else {
+		end = len(keys)
+	}
+
+	return keys[start:end], nextCursor, nil
+}
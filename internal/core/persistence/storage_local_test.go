@@ -0,0 +1,113 @@
+package persistence
+
+import (
+	"testing"
+)
+
+func newTestLocalStorage(t *testing.T) *LocalStorage {
+	t.Helper()
+
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	return s
+}
+
+func TestLocalStorageSaveLoadDeleteRoundTrip(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	if err := s.Save("tenant-a", "plugin-a", "greeting", []byte("hello")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	data, err := s.Load("tenant-a", "plugin-a", "greeting")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("Load returned %q, want %q", data, "hello")
+	}
+
+	size, err := s.StateSize("tenant-a", "plugin-a", "greeting")
+	if err != nil {
+		t.Fatalf("StateSize: %v", err)
+	}
+	if size != int64(len("hello")) {
+		t.Fatalf("StateSize = %d, want %d", size, len("hello"))
+	}
+
+	if err := s.Delete("tenant-a", "plugin-a", "greeting"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Load("tenant-a", "plugin-a", "greeting"); err == nil {
+		t.Fatalf("Load after Delete: expected an error, got nil")
+	}
+}
+
+func TestLocalStorageTenantIsolation(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	if err := s.Save("tenant-a", "plugin-a", "key", []byte("a")); err != nil {
+		t.Fatalf("Save tenant-a: %v", err)
+	}
+	if err := s.Save("tenant-b", "plugin-a", "key", []byte("b")); err != nil {
+		t.Fatalf("Save tenant-b: %v", err)
+	}
+
+	dataA, err := s.Load("tenant-a", "plugin-a", "key")
+	if err != nil {
+		t.Fatalf("Load tenant-a: %v", err)
+	}
+	dataB, err := s.Load("tenant-b", "plugin-a", "key")
+	if err != nil {
+		t.Fatalf("Load tenant-b: %v", err)
+	}
+
+	if string(dataA) != "a" || string(dataB) != "b" {
+		t.Fatalf("tenant data crossed over: tenant-a=%q tenant-b=%q", dataA, dataB)
+	}
+
+	if err := s.Delete("tenant-a", "plugin-a", "key"); err != nil {
+		t.Fatalf("Delete tenant-a: %v", err)
+	}
+	if _, err := s.Load("tenant-b", "plugin-a", "key"); err != nil {
+		t.Fatalf("Load tenant-b after deleting tenant-a's key: %v", err)
+	}
+}
+
+func TestLocalStorageRejectsPathTraversal(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	cases := []struct {
+		name     string
+		tenantId string
+		pluginId string
+		key      string
+	}{
+		{"traversal key", "tenant-a", "plugin-a", "../../../../etc/cron.d/x"},
+		{"traversal tenant", "../escape", "plugin-a", "key"},
+		{"traversal plugin", "tenant-a", "../escape", "key"},
+		{"dot key", "tenant-a", "plugin-a", "."},
+		{"dot-dot key segment", "tenant-a", "plugin-a", "sub/../../escape"},
+		{"empty tenant", "", "plugin-a", "key"},
+		{"separator in tenant", "tenant/a", "plugin-a", "key"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := s.Save(tc.tenantId, tc.pluginId, tc.key, []byte("x")); err == nil {
+				t.Fatalf("Save(%q, %q, %q): expected an error, got nil", tc.tenantId, tc.pluginId, tc.key)
+			}
+			if _, err := s.Load(tc.tenantId, tc.pluginId, tc.key); err == nil {
+				t.Fatalf("Load(%q, %q, %q): expected an error, got nil", tc.tenantId, tc.pluginId, tc.key)
+			}
+			if err := s.Delete(tc.tenantId, tc.pluginId, tc.key); err == nil {
+				t.Fatalf("Delete(%q, %q, %q): expected an error, got nil", tc.tenantId, tc.pluginId, tc.key)
+			}
+			if _, err := s.StateSize(tc.tenantId, tc.pluginId, tc.key); err == nil {
+				t.Fatalf("StateSize(%q, %q, %q): expected an error, got nil", tc.tenantId, tc.pluginId, tc.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	s3RetryAttempts = 3
+	s3RetryBaseWait = 200 * time.Millisecond
+)
+
+// S3StorageConfig configures the s3:// PersistenceStorage backend. Endpoint
+// may be left empty to use AWS's default resolver, or set to point at an
+// S3-compatible service (MinIO, R2, etc).
+type S3StorageConfig struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Bucket          string
+	PathPrefix      string
+
+	// URLPrefix/URLSuffix are prepended/appended when building signed read
+	// URLs for operators that proxy or CDN-front the bucket.
+	URLPrefix string
+	URLSuffix string
+}
+
+// s3API is the subset of *s3.Client Save/Load/Delete/List/StateSize need.
+// Narrowing to an interface lets tests back S3Storage with a fake instead of
+// a real bucket.
+type s3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// s3Presigner is the subset of *s3.PresignClient SignedURL needs.
+type s3Presigner interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// S3Storage implements PersistenceStorage backed by an S3-compatible bucket.
+type S3Storage struct {
+	client    s3API
+	presigner s3Presigner
+	bucket    string
+	prefix    string
+	cfg       S3StorageConfig
+}
+
+// NewS3Storage builds an S3Storage. bucket is the path component parsed out
+// of the s3:// uri (s3://<bucket>/<path-prefix>); cfg.Bucket overrides it
+// when set explicitly.
+func NewS3Storage(bucket string, cfg S3StorageConfig) (*S3Storage, error) {
+	bucketName, pathPrefix, _ := strings.Cut(bucket, "/")
+	if cfg.Bucket != "" {
+		bucketName = cfg.Bucket
+	}
+	if cfg.PathPrefix != "" {
+		pathPrefix = cfg.PathPrefix
+	}
+	if bucketName == "" {
+		return nil, fmt.Errorf("s3 persistence storage requires a bucket")
+	}
+
+	optFns := []func(*config.LoadOptions) error{}
+	if cfg.Region != "" {
+		optFns = append(optFns, config.WithRegion(cfg.Region))
+	}
+	if cfg.AccessKeyID != "" && cfg.SecretAccessKey != "" {
+		optFns = append(optFns, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		))
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.Background(), optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	return &S3Storage{
+		client:    client,
+		presigner: s3.NewPresignClient(client),
+		bucket:    bucketName,
+		prefix:    pathPrefix,
+		cfg:       cfg,
+	}, nil
+}
+
+func (s *S3Storage) key(tenantId string, pluginId string, key string) string {
+	return objectKey(s.prefix, tenantId, pluginId, key)
+}
+
+func (s *S3Storage) Save(tenantId string, pluginId string, key string, data []byte) error {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	return withRetry(s3RetryAttempts, s3RetryBaseWait, func() error {
+		_, err := s.client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(objKey),
+			Body:   bytes.NewReader(data),
+		})
+		return err
+	})
+}
+
+func (s *S3Storage) Load(tenantId string, pluginId string, key string) ([]byte, error) {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return nil, err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	var data []byte
+	err := withRetry(s3RetryAttempts, s3RetryBaseWait, func() error {
+		out, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(objKey),
+		})
+		if err != nil {
+			return err
+		}
+		defer out.Body.Close()
+
+		data, err = io.ReadAll(out.Body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *S3Storage) Delete(tenantId string, pluginId string, key string) error {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	return withRetry(s3RetryAttempts, s3RetryBaseWait, func() error {
+		_, err := s.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(objKey),
+		})
+		var notFound *types.NoSuchKey
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+// SupportsEfficientList reports true: ListObjectsV2 scans by prefix natively.
+func (s *S3Storage) SupportsEfficientList() bool {
+	return true
+}
+
+func (s *S3Storage) List(tenantId string, pluginId string, prefix string, cursor string, limit int) ([]KeyInfo, string, error) {
+	if err := validatePathComponent("tenantId", tenantId); err != nil {
+		return nil, "", err
+	}
+	if err := validatePathComponent("pluginId", pluginId); err != nil {
+		return nil, "", err
+	}
+
+	listPrefix := s.key(tenantId, pluginId, prefix)
+	trimPrefix := s.key(tenantId, pluginId, "")
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.bucket),
+		Prefix:  aws.String(listPrefix),
+		MaxKeys: aws.Int32(int32(limit)),
+	}
+	if cursor != "" {
+		input.ContinuationToken = aws.String(cursor)
+	}
+
+	var out *s3.ListObjectsV2Output
+	err := withRetry(s3RetryAttempts, s3RetryBaseWait, func() error {
+		var err error
+		out, err = s.client.ListObjectsV2(context.Background(), input)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	keys := make([]KeyInfo, 0, len(out.Contents))
+	for _, obj := range out.Contents {
+		keys = append(keys, KeyInfo{
+			Key:       strings.TrimPrefix(aws.ToString(obj.Key), trimPrefix),
+			Size:      aws.ToInt64(obj.Size),
+			UpdatedAt: aws.ToTime(obj.LastModified),
+		})
+	}
+
+	nextCursor := ""
+	if aws.ToBool(out.IsTruncated) {
+		nextCursor = aws.ToString(out.NextContinuationToken)
+	}
+
+	return keys, nextCursor, nil
+}
+
+func (s *S3Storage) StateSize(tenantId string, pluginId string, key string) (int64, error) {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return 0, err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	var size int64
+	err := withRetry(s3RetryAttempts, s3RetryBaseWait, func() error {
+		out, err := s.client.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(objKey),
+		})
+		if err != nil {
+			return err
+		}
+		size = aws.ToInt64(out.ContentLength)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// SignedURL returns a time-limited, pre-signed GET URL for (tenantId,
+// pluginId, key), with cfg.URLPrefix/URLSuffix applied so operators that
+// proxy or CDN-front the bucket can rewrite it to a reachable address.
+func (s *S3Storage) SignedURL(tenantId string, pluginId string, key string, expiresIn time.Duration) (string, error) {
+	if err := validateObjectPath(tenantId, pluginId, key); err != nil {
+		return "", err
+	}
+
+	objKey := s.key(tenantId, pluginId, key)
+
+	var signed *v4.PresignedHTTPRequest
+	err := withRetry(s3RetryAttempts, s3RetryBaseWait, func() error {
+		var err error
+		signed, err = s.presigner.PresignGetObject(context.Background(), &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(objKey),
+		}, s3.WithPresignExpires(expiresIn))
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.cfg.URLPrefix + signed.URL + s.cfg.URLSuffix, nil
+}
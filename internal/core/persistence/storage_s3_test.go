@@ -0,0 +1,129 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// fakeS3API is an in-memory stand-in for s3API, keyed by object key, so
+// Save/Load can be exercised without a real bucket.
+type fakeS3API struct {
+	objects map[string][]byte
+}
+
+func newFakeS3API() *fakeS3API {
+	return &fakeS3API{objects: make(map[string][]byte)}
+}
+
+func (f *fakeS3API) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[aws.ToString(params.Key)] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3API) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	data, ok := f.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (f *fakeS3API) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	delete(f.objects, aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3API) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return &s3.ListObjectsV2Output{}, nil
+}
+
+func (f *fakeS3API) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	data, ok := f.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(data)))}, nil
+}
+
+type fakeS3Presigner struct{}
+
+func (fakeS3Presigner) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	return &v4.PresignedHTTPRequest{URL: "https://example.invalid/" + aws.ToString(params.Key)}, nil
+}
+
+func newTestS3Storage() *S3Storage {
+	return &S3Storage{
+		client:    newFakeS3API(),
+		presigner: fakeS3Presigner{},
+		bucket:    "test-bucket",
+	}
+}
+
+func TestS3StorageSaveLoadLargeBlob(t *testing.T) {
+	s := newTestS3Storage()
+
+	// 8MiB, large enough that a naive implementation buffering into a
+	// fixed-size intermediate would truncate or corrupt it.
+	large := bytes.Repeat([]byte("0123456789abcdef"), (8<<20)/16)
+
+	if err := s.Save("tenant", "plugin", "blob", large); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := s.Load("tenant", "plugin", "blob")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !bytes.Equal(got, large) {
+		t.Fatalf("Load returned %d bytes, want %d bytes matching the original", len(got), len(large))
+	}
+
+	size, err := s.StateSize("tenant", "plugin", "blob")
+	if err != nil {
+		t.Fatalf("StateSize: %v", err)
+	}
+	if size != int64(len(large)) {
+		t.Fatalf("StateSize = %d, want %d", size, len(large))
+	}
+}
+
+func TestS3StorageDeleteRemovesObject(t *testing.T) {
+	s := newTestS3Storage()
+
+	if err := s.Save("tenant", "plugin", "key", []byte("data")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := s.Delete("tenant", "plugin", "key"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Load("tenant", "plugin", "key"); err == nil {
+		t.Fatalf("Load after Delete: expected an error, got nil")
+	}
+}
+
+func TestS3StorageSignedURLUsesPrefixAndSuffix(t *testing.T) {
+	s := newTestS3Storage()
+	s.cfg = S3StorageConfig{URLPrefix: "https://cdn.example.com/", URLSuffix: "?cache=1"}
+
+	url, err := s.SignedURL("tenant", "plugin", "key", 0)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	if !bytes.HasPrefix([]byte(url), []byte(s.cfg.URLPrefix)) {
+		t.Fatalf("SignedURL() = %q, want prefix %q", url, s.cfg.URLPrefix)
+	}
+	if !bytes.HasSuffix([]byte(url), []byte(s.cfg.URLSuffix)) {
+		t.Fatalf("SignedURL() = %q, want suffix %q", url, s.cfg.URLSuffix)
+	}
+}
@@ -0,0 +1,73 @@
+package persistence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithRetrySucceedsAfterTransientErrors(t *testing.T) {
+	attempts := 0
+	err := withRetry(3, time.Millisecond, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withRetry: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestWithRetryReturnsLastErrorAfterExhausted(t *testing.T) {
+	attempts := 0
+	wantErr := errors.New("permanent")
+
+	err := withRetry(3, time.Millisecond, func() error {
+		attempts++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("withRetry error = %v, want %v", err, wantErr)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestValidateObjectPathRejectsTraversal(t *testing.T) {
+	cases := []struct {
+		name     string
+		tenantId string
+		pluginId string
+		key      string
+	}{
+		{"dot-dot key", "tenant", "plugin", "../../etc/passwd"},
+		{"dot-dot segment", "tenant", "plugin", "a/../../b"},
+		{"dot key", "tenant", "plugin", "."},
+		{"empty key", "tenant", "plugin", ""},
+		{"dot-dot tenant", "..", "plugin", "key"},
+		{"separator in plugin", "tenant", "a/b", "key"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateObjectPath(tc.tenantId, tc.pluginId, tc.key); err == nil {
+				t.Fatalf("validateObjectPath(%q, %q, %q): expected an error, got nil", tc.tenantId, tc.pluginId, tc.key)
+			}
+		})
+	}
+}
+
+func TestValidateObjectPathAcceptsOrdinaryKeys(t *testing.T) {
+	cases := []string{"key", "nested/key", "a.b.c", "v1/2024/report.json"}
+	for _, key := range cases {
+		if err := validateObjectPath("tenant", "plugin", key); err != nil {
+			t.Fatalf("validateObjectPath(tenant, plugin, %q): %v", key, err)
+		}
+	}
+}
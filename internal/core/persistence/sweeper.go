@@ -0,0 +1,100 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/langgenius/dify-plugin-daemon/internal/db"
+	"github.com/langgenius/dify-plugin-daemon/internal/types/models"
+)
+
+const (
+	// sweepInterval is how often Run scans for expired keys.
+	sweepInterval = time.Minute
+
+	// sweepBatchSize caps how many expired rows each scan fetches, so a
+	// large backlog is loaded and processed in bounded batches rather than
+	// all at once.
+	sweepBatchSize = 100
+)
+
+// Run starts the background expiration sweeper, blocking until ctx is
+// cancelled. Callers are expected to invoke it in its own goroutine, e.g.
+// `go persistence.Run(ctx)`.
+func (c *Persistence) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.sweepExpired()
+			c.sweepExpiredPacks()
+		}
+	}
+}
+
+// sweepExpired deletes every TenantStorageEntry whose expiry has passed,
+// freeing the underlying blob and the tenant's accounted size for each one.
+// It keeps sweeping batches until a scan comes back empty.
+func (c *Persistence) sweepExpired() {
+	for {
+		entries, err := db.GetAll[models.TenantStorageEntry](
+			db.LessThan("expires_at", time.Now()),
+			db.Limit(sweepBatchSize),
+		)
+		if err != nil || len(entries) == 0 {
+			return
+		}
+
+		for _, entry := range entries {
+			c.expireEntry(entry)
+		}
+
+		if len(entries) < sweepBatchSize {
+			return
+		}
+	}
+}
+
+// expireEntry deletes the blob backing entry and, inside a single
+// transaction, removes its TenantStorageEntry row and decrements
+// TenantStorage.Size. The row deletion and the decrement are only performed
+// together: if Persistence.Delete already raced this sweep and removed the
+// row first, deletedRows comes back 0 and the decrement is skipped, so the
+// tenant's accounted size is never double-subtracted for the same key.
+func (c *Persistence) expireEntry(entry models.TenantStorageEntry) {
+	if err := c.storage.Delete(entry.TenantID, entry.PluginID, entry.Key); err != nil {
+		return
+	}
+
+	err := db.Transaction(func() error {
+		deletedRows, err := db.Delete[models.TenantStorageEntry](
+			db.Equal("tenant_id", entry.TenantID),
+			db.Equal("plugin_id", entry.PluginID),
+			db.Equal("key", entry.Key),
+		)
+		if err != nil {
+			return err
+		}
+		if deletedRows == 0 {
+			return nil
+		}
+
+		return db.Run(
+			db.Model(&models.TenantStorage{}),
+			db.Equal("tenant_id", entry.TenantID),
+			db.Equal("plugin_id", entry.PluginID),
+			db.Dec(map[string]int64{"size": entry.Size}),
+		)
+	})
+	if err != nil {
+		return
+	}
+
+	if err := c.invalidateCache(entry.TenantID, entry.PluginID, entry.Key); err != nil {
+		return
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// TenantStorageEntry tracks the size and optional expiry of a single key
+// persisted through Persistence. It exists alongside the aggregate
+// TenantStorage row so that overwriting a key can compute a size delta
+// instead of double-counting, and so the expiration sweeper has something
+// to scan.
+type TenantStorageEntry struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TenantID  string     `json:"tenant_id" gorm:"index:idx_tenant_storage_entry,unique,priority:1;not null"`
+	PluginID  string     `json:"plugin_id" gorm:"index:idx_tenant_storage_entry,unique,priority:2;not null"`
+	Key       string     `json:"key" gorm:"index:idx_tenant_storage_entry,unique,priority:3;not null"`
+	Size      int64      `json:"size" gorm:"not null"`
+	ExpiresAt *time.Time `json:"expires_at" gorm:"index"`
+
+	// OverQuota is set by the storage pack sweeper when an expiring pack
+	// leaves the tenant over its effective quota. Entries flagged this way
+	// are kept (not deleted) but Persistence.Save refuses further writes for
+	// the tenant until enough data is removed to clear the flag.
+	OverQuota bool `json:"over_quota" gorm:"index;not null;default:false"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (TenantStorageEntry) TableName() string {
+	return "tenant_storage_entries"
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// TenantStoragePack grants a tenant extra storage on top of the deployment's
+// base quota for the window [ActiveAt, ExpiresAt), letting operators
+// sell/grant temporary storage boosts without redeploying.
+type TenantStoragePack struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	TenantID       string    `json:"tenant_id" gorm:"index;not null"`
+	ExtraSizeBytes int64     `json:"extra_size_bytes" gorm:"not null"`
+	ActiveAt       time.Time `json:"active_at" gorm:"not null"`
+	ExpiresAt      time.Time `json:"expires_at" gorm:"index;not null"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (TenantStoragePack) TableName() string {
+	return "tenant_storage_packs"
+}
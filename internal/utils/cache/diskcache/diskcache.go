@@ -0,0 +1,248 @@
+// Package diskcache implements a bbolt-backed second-tier cache, sitting
+// between an in-memory cache and slower remote storage. It trades a bit of
+// disk I/O for cold-start warmth without holding everything in RAM.
+package diskcache
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// headerSize is the fixed-width header bbolt stores ahead of every value:
+// 8 bytes for the payload size, 8 bytes for the insertion time (unix nano).
+const headerSize = 16
+
+// Cache is a disk-backed cache bounded by total byte size. One bbolt bucket
+// is used per tenant; keys within a bucket are "<pluginId>/<key>". An
+// in-memory LRU mirrors the keys written since Open so eviction never has to
+// scan bbolt.
+type Cache struct {
+	db *bbolt.DB
+
+	mu        sync.Mutex
+	lru       *list.List // of *lruEntry, front = most recently used
+	index     map[string]*list.Element
+	maxBytes  int64
+	usedBytes atomic.Int64
+}
+
+type lruEntry struct {
+	tenantId string
+	pluginId string
+	key      string
+	size     int64
+}
+
+// Open creates (or reopens) a Cache backed by the bbolt file at path,
+// bounded to maxBytes of payload data. Entries already in a reopened file
+// are served by Get but are not loaded into the LRU: they do not count
+// towards maxBytes and are never chosen for eviction.
+func Open(path string, maxBytes int64) (*Cache, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{
+		db:       db,
+		lru:      list.New(),
+		index:    make(map[string]*list.Element),
+		maxBytes: maxBytes,
+	}, nil
+}
+
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+func indexKey(tenantId string, pluginId string, key string) string {
+	return fmt.Sprintf("%s:%s:%s", tenantId, pluginId, key)
+}
+
+func bucketKey(pluginId string, key string) []byte {
+	return []byte(pluginId + "/" + key)
+}
+
+func encode(data []byte) []byte {
+	buf := make([]byte, headerSize+len(data))
+	binary.BigEndian.PutUint64(buf[0:8], uint64(len(data)))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(time.Now().UnixNano()))
+	copy(buf[headerSize:], data)
+	return buf
+}
+
+func decode(raw []byte) ([]byte, error) {
+	if len(raw) < headerSize {
+		return nil, fmt.Errorf("diskcache: corrupt entry: truncated header")
+	}
+	size := binary.BigEndian.Uint64(raw[0:8])
+	if uint64(len(raw)-headerSize) != size {
+		return nil, fmt.Errorf("diskcache: corrupt entry: size mismatch")
+	}
+	return raw[headerSize:], nil
+}
+
+// Get returns the cached value for (tenantId, pluginId, key), if present.
+func (c *Cache) Get(tenantId string, pluginId string, key string) ([]byte, bool) {
+	var raw []byte
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(tenantId))
+		if bucket == nil {
+			return nil
+		}
+		if v := bucket.Get(bucketKey(pluginId, key)); v != nil {
+			raw = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil || raw == nil {
+		return nil, false
+	}
+
+	data, err := decode(raw)
+	if err != nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	if el, ok := c.index[indexKey(tenantId, pluginId, key)]; ok {
+		c.lru.MoveToFront(el)
+	}
+	c.mu.Unlock()
+
+	return data, true
+}
+
+// Set stores data under (tenantId, pluginId, key), evicting the
+// least-recently-used entries first if doing so would exceed maxBytes.
+//
+// mu is held for the whole operation, including the bbolt transaction, so a
+// concurrent Set for a key chosen for eviction can never race with (and be
+// silently undone by) the transaction that evicts it. The in-memory
+// lru/index/usedBytes are only mutated after db.Update succeeds: if the
+// write fails (disk full, read-only remount, ...), every evicted entry's
+// bytes are still physically on disk, so they must still be reachable from
+// lru/index and still counted in usedBytes.
+func (c *Cache) Set(tenantId string, pluginId string, key string, data []byte) error {
+	idxKey := indexKey(tenantId, pluginId, key)
+	size := int64(len(data))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var previousSize int64
+	if el, ok := c.index[idxKey]; ok {
+		previousSize = el.Value.(*lruEntry).size
+	}
+
+	evicted := c.selectEvictions(size-previousSize, idxKey)
+
+	if err := c.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(tenantId))
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(bucketKey(pluginId, key), encode(data)); err != nil {
+			return err
+		}
+
+		for _, e := range evicted {
+			if evictBucket := tx.Bucket([]byte(e.tenantId)); evictBucket != nil {
+				if err := evictBucket.Delete(bucketKey(e.pluginId, e.key)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// the write committed: it's now safe to drop the evicted entries and the
+	// overwritten entry's old list position from memory.
+	if el, ok := c.index[idxKey]; ok {
+		c.lru.Remove(el)
+		delete(c.index, idxKey)
+	}
+	for _, e := range evicted {
+		evictedKey := indexKey(e.tenantId, e.pluginId, e.key)
+		if el, ok := c.index[evictedKey]; ok {
+			c.lru.Remove(el)
+			delete(c.index, evictedKey)
+		}
+		c.usedBytes.Add(-e.size)
+	}
+
+	el := c.lru.PushFront(&lruEntry{tenantId: tenantId, pluginId: pluginId, key: key, size: size})
+	c.index[idxKey] = el
+	c.usedBytes.Add(size - previousSize)
+
+	return nil
+}
+
+// Del removes (tenantId, pluginId, key) from the cache, if present. mu is
+// held across the bbolt transaction for the same reason as in Set.
+func (c *Cache) Del(tenantId string, pluginId string, key string) error {
+	idxKey := indexKey(tenantId, pluginId, key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the key is deleted from bbolt even when the LRU doesn't track it (an
+	// entry left in the file from before Open), since Get still serves those.
+	if err := c.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(tenantId))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete(bucketKey(pluginId, key))
+	}); err != nil {
+		return err
+	}
+
+	if el, ok := c.index[idxKey]; ok {
+		c.lru.Remove(el)
+		delete(c.index, idxKey)
+		c.usedBytes.Add(-el.Value.(*lruEntry).size)
+	}
+	return nil
+}
+
+// Size returns the total number of payload bytes currently cached.
+func (c *Cache) Size() int64 {
+	return c.usedBytes.Load()
+}
+
+// selectEvictions picks least-recently-used entries off the back of the LRU
+// list until adding addedBytes would fit within maxBytes, skipping the entry
+// for excludeKey (the key Set is about to overwrite, if any, which is still
+// in the list at this point). It only peeks: lru, index, and usedBytes are
+// left untouched, so a caller can still back out of the eviction (e.g. if
+// the write that depends on it then fails) without having to undo anything.
+// Caller must hold c.mu.
+func (c *Cache) selectEvictions(addedBytes int64, excludeKey string) []*lruEntry {
+	if c.maxBytes <= 0 {
+		return nil
+	}
+
+	projected := c.usedBytes.Load() + addedBytes
+	var evicted []*lruEntry
+
+	for el := c.lru.Back(); el != nil && projected > c.maxBytes; el = el.Prev() {
+		e := el.Value.(*lruEntry)
+		if indexKey(e.tenantId, e.pluginId, e.key) == excludeKey {
+			continue
+		}
+
+		evicted = append(evicted, e)
+		projected -= e.size
+	}
+
+	return evicted
+}
@@ -0,0 +1,142 @@
+package diskcache
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestCache(t *testing.T, maxBytes int64) *Cache {
+	t.Helper()
+
+	c, err := Open(filepath.Join(t.TempDir(), "cache.db"), maxBytes)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestCacheGetSetRoundTrip(t *testing.T) {
+	c := newTestCache(t, 1024)
+
+	if err := c.Set("tenant", "plugin", "key", []byte("hello")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	data, ok := c.Get("tenant", "plugin", "key")
+	if !ok || string(data) != "hello" {
+		t.Fatalf("Get = (%q, %v), want (\"hello\", true)", data, ok)
+	}
+
+	if size := c.Size(); size != int64(len("hello")) {
+		t.Fatalf("Size() = %d, want %d", size, len("hello"))
+	}
+}
+
+func TestCacheDel(t *testing.T) {
+	c := newTestCache(t, 1024)
+
+	_ = c.Set("tenant", "plugin", "key", []byte("hello"))
+	if err := c.Del("tenant", "plugin", "key"); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+
+	if _, ok := c.Get("tenant", "plugin", "key"); ok {
+		t.Fatalf("Get after Del = ok, want a miss")
+	}
+	if size := c.Size(); size != 0 {
+		t.Fatalf("Size() after Del = %d, want 0", size)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	// three 1-byte entries over a 2-byte budget: the least-recently-used one
+	// (never re-Get after insertion, and not the most recent) must go.
+	c := newTestCache(t, 2)
+
+	_ = c.Set("t", "p", "a", []byte("1"))
+	_ = c.Set("t", "p", "b", []byte("1"))
+
+	// touch "a" so it's more recently used than "b".
+	c.Get("t", "p", "a")
+
+	_ = c.Set("t", "p", "c", []byte("1"))
+
+	if _, ok := c.Get("t", "p", "b"); ok {
+		t.Fatalf("Get(b) = ok, want b to have been evicted as least-recently-used")
+	}
+	if _, ok := c.Get("t", "p", "a"); !ok {
+		t.Fatalf("Get(a) = miss, want a to have survived eviction")
+	}
+	if _, ok := c.Get("t", "p", "c"); !ok {
+		t.Fatalf("Get(c) = miss, want c to have survived eviction")
+	}
+	if size := c.Size(); size > 2 {
+		t.Fatalf("Size() = %d, want <= 2", size)
+	}
+}
+
+func TestCacheOverwriteAdjustsByteAccounting(t *testing.T) {
+	c := newTestCache(t, 1024)
+
+	_ = c.Set("t", "p", "key", make([]byte, 10))
+	if size := c.Size(); size != 10 {
+		t.Fatalf("Size() after first Set = %d, want 10", size)
+	}
+
+	_ = c.Set("t", "p", "key", make([]byte, 3))
+	if size := c.Size(); size != 3 {
+		t.Fatalf("Size() after overwrite = %d, want 3", size)
+	}
+}
+
+func TestCacheTenantIsolation(t *testing.T) {
+	c := newTestCache(t, 1024)
+
+	_ = c.Set("tenant-a", "plugin", "key", []byte("a"))
+	_ = c.Set("tenant-b", "plugin", "key", []byte("b"))
+
+	dataA, _ := c.Get("tenant-a", "plugin", "key")
+	dataB, _ := c.Get("tenant-b", "plugin", "key")
+
+	if string(dataA) != "a" || string(dataB) != "b" {
+		t.Fatalf("tenant data crossed over: tenant-a=%q tenant-b=%q", dataA, dataB)
+	}
+}
+
+func TestSelectEvictionsExcludesOverwrittenKey(t *testing.T) {
+	c := newTestCache(t, 2)
+
+	_ = c.Set("t", "p", "a", []byte("1"))
+
+	idxKey := indexKey("t", "p", "a")
+	// 1 byte used + 2 added exceeds the 2-byte budget, so the eviction loop
+	// runs and reaches "a", the only entry.
+	evicted := c.selectEvictions(2, idxKey)
+
+	for _, e := range evicted {
+		if indexKey(e.tenantId, e.pluginId, e.key) == idxKey {
+			t.Fatalf("selectEvictions returned the entry being overwritten (%s) as a victim", idxKey)
+		}
+	}
+}
+
+func TestSelectEvictionsDoesNotMutateStateUntilCommitted(t *testing.T) {
+	c := newTestCache(t, 1)
+
+	_ = c.Set("t", "p", "a", []byte("1"))
+
+	sizeBefore := c.Size()
+	lenBefore := c.lru.Len()
+
+	// selecting victims for a hypothetical write must not remove them from
+	// the LRU or adjust usedBytes by itself — only a successful Set/Del may.
+	_ = c.selectEvictions(1, "")
+
+	if c.Size() != sizeBefore {
+		t.Fatalf("Size() changed after selectEvictions alone: got %d, want %d", c.Size(), sizeBefore)
+	}
+	if c.lru.Len() != lenBefore {
+		t.Fatalf("lru.Len() changed after selectEvictions alone: got %d, want %d", c.lru.Len(), lenBefore)
+	}
+}
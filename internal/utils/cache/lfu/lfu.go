@@ -0,0 +1,230 @@
+// Package lfu implements an in-process, byte-budgeted LFU cache.
+//
+// Unlike the generic redis-backed internal/utils/cache package, entries here
+// never leave the process and eviction is driven purely by a configurable
+// byte budget rather than a fixed entry count or TTL.
+package lfu
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is the interface Persistence depends on, allowing the LFU
+// implementation to be swapped out (e.g. in tests) without touching callers.
+type Cache interface {
+	// Get returns the cached value for key and bumps its frequency. ok is
+	// false if the key is absent or has expired.
+	Get(key string) (value []byte, ok bool)
+	// Set stores value under key, evicting least-frequently (then
+	// least-recently) used entries until the cache fits within its byte
+	// budget. ttl of zero means the entry never expires on its own.
+	Set(key string, value []byte, ttl time.Duration)
+	// Del removes key from the cache, if present.
+	Del(key string)
+	// Len returns the number of entries currently cached.
+	Len() int
+	// Size returns the total number of bytes currently cached.
+	Size() int64
+}
+
+type entry struct {
+	key      string
+	value    []byte
+	expireAt time.Time // zero value means "no expiry"
+	freq     int
+
+	itemElem *list.Element // element in this entry's freqNode.items list
+	freqElem *list.Element // element in freqList that owns itemElem
+}
+
+func (e *entry) expired() bool {
+	return !e.expireAt.IsZero() && time.Now().After(e.expireAt)
+}
+
+type freqNode struct {
+	freq     int
+	items    *list.List    // of *entry
+	freqElem *list.Element // this node's element in freqList
+}
+
+// LFUCache is a thread-safe LFU cache bounded by total byte size rather than
+// entry count. Frequency buckets are kept in an ordered doubly-linked list
+// (freqList) so the least-frequently-used bucket is always O(1) to find, and
+// the oldest entry within a bucket is always at the front of its items list.
+type LFUCache struct {
+	mu sync.Mutex
+
+	maxBytes  int64
+	usedBytes int64
+
+	entries  map[string]*entry
+	freqList *list.List // of *freqNode, ascending by freq
+
+	hits, misses, evictions int64
+}
+
+// New creates an LFUCache bounded to maxBytes of cached value data.
+func New(maxBytes int64) *LFUCache {
+	return &LFUCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*entry),
+		freqList: list.New(),
+	}
+}
+
+func (c *LFUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		observeMiss()
+		return nil, false
+	}
+	if e.expired() {
+		c.removeEntry(e)
+		c.misses++
+		observeMiss()
+		return nil, false
+	}
+
+	c.bump(e)
+	c.hits++
+	observeHit()
+
+	return e.value, true
+}
+
+func (c *LFUCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := int64(len(value))
+
+	if old, ok := c.entries[key]; ok {
+		c.usedBytes -= int64(len(old.value))
+		old.value = value
+		if ttl > 0 {
+			old.expireAt = time.Now().Add(ttl)
+		} else {
+			old.expireAt = time.Time{}
+		}
+		c.usedBytes += size
+		c.bump(old)
+		c.evictUntilFits()
+		observeSize(c.usedBytes)
+		return
+	}
+
+	e := &entry{key: key, value: value, freq: 1}
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	}
+
+	node := c.freqNode(1)
+	e.itemElem = node.items.PushBack(e)
+	e.freqElem = node.freqElem
+
+	c.entries[key] = e
+	c.usedBytes += size
+
+	c.evictUntilFits()
+	observeSize(c.usedBytes)
+}
+
+func (c *LFUCache) Del(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.removeEntry(e)
+	}
+}
+
+func (c *LFUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+func (c *LFUCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.usedBytes
+}
+
+// bump moves e from its current frequency bucket to freq+1, creating the
+// bucket if it doesn't exist yet.
+func (c *LFUCache) bump(e *entry) {
+	oldNode := e.freqElem.Value.(*freqNode)
+	oldNode.items.Remove(e.itemElem)
+	if oldNode.items.Len() == 0 {
+		c.freqList.Remove(e.freqElem)
+	}
+
+	e.freq++
+	node := c.freqNode(e.freq)
+	e.itemElem = node.items.PushBack(e)
+	e.freqElem = node.freqElem
+}
+
+// freqNode returns the freqList bucket for freq, creating and inserting it
+// in ascending order if it doesn't already exist.
+func (c *LFUCache) freqNode(freq int) *freqNode {
+	for el := c.freqList.Front(); el != nil; el = el.Next() {
+		n := el.Value.(*freqNode)
+		if n.freq == freq {
+			return n
+		}
+		if n.freq > freq {
+			n = &freqNode{freq: freq, items: list.New()}
+			el = c.freqList.InsertBefore(n, el)
+			n.freqElem = el
+			return n
+		}
+	}
+
+	n := &freqNode{freq: freq, items: list.New()}
+	n.freqElem = c.freqList.PushBack(n)
+	return n
+}
+
+// evictUntilFits evicts the least-frequently, then least-recently used
+// entries until the cache is back within its byte budget.
+func (c *LFUCache) evictUntilFits() {
+	for c.maxBytes > 0 && c.usedBytes > c.maxBytes {
+		el := c.freqList.Front()
+		if el == nil {
+			return
+		}
+		node := el.Value.(*freqNode)
+		front := node.items.Front()
+		if front == nil {
+			c.freqList.Remove(el)
+			continue
+		}
+
+		c.removeEntry(front.Value.(*entry))
+		c.evictions++
+		observeEviction()
+	}
+}
+
+// removeEntry unlinks e from its frequency bucket and the entries map and
+// reclaims its bytes. Caller must hold c.mu.
+func (c *LFUCache) removeEntry(e *entry) {
+	node := e.freqElem.Value.(*freqNode)
+	node.items.Remove(e.itemElem)
+	if node.items.Len() == 0 {
+		c.freqList.Remove(e.freqElem)
+	}
+
+	delete(c.entries, e.key)
+	c.usedBytes -= int64(len(e.value))
+	observeSize(c.usedBytes)
+}
@@ -0,0 +1,135 @@
+package lfu
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLFUCacheGetSetRoundTrip(t *testing.T) {
+	c := New(1024)
+
+	c.Set("a", []byte("1"), 0)
+
+	v, ok := c.Get("a")
+	if !ok || string(v) != "1" {
+		t.Fatalf("Get(a) = (%q, %v), want (\"1\", true)", v, ok)
+	}
+
+	if _, ok := c.Get("missing"); ok {
+		t.Fatalf("Get(missing) = ok, want a miss")
+	}
+}
+
+func TestLFUCacheEvictsLeastFrequentlyUsedFirst(t *testing.T) {
+	// each entry is 1 byte; a 2-byte budget holds exactly two of them.
+	c := New(2)
+
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("1"), 0)
+
+	// bump "a"'s frequency above "b"'s before a third entry forces an
+	// eviction.
+	c.Get("a")
+
+	c.Set("c", []byte("1"), 0)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("Get(b) = ok, want b to have been evicted as the least-frequently-used entry")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("Get(a) = miss, want a to have survived eviction")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Fatalf("Get(c) = miss, want c to have survived eviction")
+	}
+}
+
+func TestLFUCacheEvictsLeastRecentlyUsedWithinSameFrequency(t *testing.T) {
+	// three 1-byte entries, all at frequency 1 (never Get'd), over a 2-byte
+	// budget: the oldest one inserted should be evicted first.
+	c := New(2)
+
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("1"), 0)
+	c.Set("c", []byte("1"), 0)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("Get(a) = ok, want a (the oldest same-frequency entry) to have been evicted")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Fatalf("Get(b) = miss, want b to have survived eviction")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Fatalf("Get(c) = miss, want c to have survived eviction")
+	}
+}
+
+func TestLFUCacheEnforcesByteBudget(t *testing.T) {
+	c := New(10)
+
+	c.Set("a", make([]byte, 6), 0)
+	c.Set("b", make([]byte, 6), 0)
+
+	if size := c.Size(); size > 10 {
+		t.Fatalf("Size() = %d, want <= 10", size)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("Get(a) = ok, want a to have been evicted to stay within the byte budget")
+	}
+}
+
+func TestLFUCacheSetOverwriteAdjustsUsedBytes(t *testing.T) {
+	c := New(1024)
+
+	c.Set("a", make([]byte, 10), 0)
+	if size := c.Size(); size != 10 {
+		t.Fatalf("Size() after first Set = %d, want 10", size)
+	}
+
+	c.Set("a", make([]byte, 3), 0)
+	if size := c.Size(); size != 3 {
+		t.Fatalf("Size() after overwrite = %d, want 3", size)
+	}
+	if n := c.Len(); n != 1 {
+		t.Fatalf("Len() = %d, want 1", n)
+	}
+}
+
+func TestLFUCacheTTLExpiry(t *testing.T) {
+	c := New(1024)
+
+	c.Set("a", []byte("1"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("Get(a) = ok, want a to have expired")
+	}
+	if n := c.Len(); n != 0 {
+		t.Fatalf("Len() after expiry = %d, want 0", n)
+	}
+}
+
+func TestLFUCacheZeroTTLNeverExpires(t *testing.T) {
+	c := New(1024)
+
+	c.Set("a", []byte("1"), 0)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("Get(a) = miss, want a (ttl=0) to never expire")
+	}
+}
+
+func TestLFUCacheDel(t *testing.T) {
+	c := New(1024)
+
+	c.Set("a", []byte("1"), 0)
+	c.Del("a")
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("Get(a) = ok after Del, want a miss")
+	}
+	if n := c.Len(); n != 0 {
+		t.Fatalf("Len() after Del = %d, want 0", n)
+	}
+}
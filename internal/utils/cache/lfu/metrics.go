@@ -0,0 +1,30 @@
+package lfu
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	cacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "dify_plugin_daemon_persistence_lfu_cache_hits_total",
+		Help: "Number of persistence LFU cache lookups that found a live entry.",
+	})
+	cacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "dify_plugin_daemon_persistence_lfu_cache_misses_total",
+		Help: "Number of persistence LFU cache lookups that found no live entry.",
+	})
+	cacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "dify_plugin_daemon_persistence_lfu_cache_evictions_total",
+		Help: "Number of entries evicted from the persistence LFU cache to stay within its byte budget.",
+	})
+	cacheBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dify_plugin_daemon_persistence_lfu_cache_bytes",
+		Help: "Total number of bytes currently held by the persistence LFU cache.",
+	})
+)
+
+func observeHit()              { cacheHits.Inc() }
+func observeMiss()             { cacheMisses.Inc() }
+func observeEviction()         { cacheEvictions.Inc() }
+func observeSize(bytes int64)  { cacheBytes.Set(float64(bytes)) }